@@ -0,0 +1,125 @@
+// Package api serves current and historical occupancy data over HTTP so a
+// dashboard or another service can consume it without shelling out to the
+// filesystem.
+package api
+
+import (
+    "encoding/json"
+    "net/http"
+    "sync"
+    "time"
+
+    "github.com/olimci/library/store"
+)
+
+var (
+    currentMu   sync.RWMutex
+    current     any
+    currentTime time.Time
+)
+
+// SetCurrent records the most recently decoded response so GET /v1/current
+// can serve it without re-polling the upstream endpoint.
+func SetCurrent(data any, at time.Time) {
+    currentMu.Lock()
+    defer currentMu.Unlock()
+
+    current = data
+    currentTime = at
+}
+
+// NewMux builds the /v1 API, reading historical rows back from st.
+func NewMux(st store.Store) *http.ServeMux {
+    mux := http.NewServeMux()
+
+    mux.HandleFunc("/v1/current", currentHandler)
+    mux.HandleFunc("/v1/levels", levelsHandler(st))
+    mux.HandleFunc("/v1/history", historyHandler(st))
+
+    return mux
+}
+
+// Listen starts the API HTTP server on addr. It blocks until the server
+// stops, so callers should run it in its own goroutine.
+func Listen(addr string, st store.Store) error {
+    return http.ListenAndServe(addr, NewMux(st))
+}
+
+func currentHandler(w http.ResponseWriter, r *http.Request) {
+    currentMu.RLock()
+    data, at := current, currentTime
+    currentMu.RUnlock()
+
+    if data == nil {
+        http.Error(w, "no data yet", http.StatusServiceUnavailable)
+        return
+    }
+
+    writeJSON(w, struct {
+        Timestamp time.Time `json:"timestamp"`
+        Data      any       `json:"data"`
+    }{at, data})
+}
+
+func levelsHandler(st store.Store) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        levels, err := st.Levels(r.Context())
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+
+        writeJSON(w, levels)
+    }
+}
+
+func historyHandler(st store.Store) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        q, err := parseQuery(r)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+
+        samples, err := st.Query(r.Context(), q)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+
+        writeJSON(w, samples)
+    }
+}
+
+func parseQuery(r *http.Request) (store.Query, error) {
+    q := store.Query{
+        From:  time.Unix(0, 0),
+        To:    time.Now(),
+        Level: r.URL.Query().Get("level"),
+    }
+
+    if v := r.URL.Query().Get("from"); v != "" {
+        from, err := time.Parse(time.RFC3339, v)
+        if err != nil {
+            return q, err
+        }
+        q.From = from
+    }
+
+    if v := r.URL.Query().Get("to"); v != "" {
+        to, err := time.Parse(time.RFC3339, v)
+        if err != nil {
+            return q, err
+        }
+        q.To = to
+    }
+
+    return q, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(v); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
@@ -2,14 +2,19 @@ package main
 
 import (
     "context"
-    "encoding/csv"
     "encoding/json"
     "fmt"
+    "github.com/cenkalti/backoff/v4"
+    "github.com/olimci/library/api"
+    "github.com/olimci/library/metrics"
+    "github.com/olimci/library/notify"
+    "github.com/olimci/library/rotator"
+    "github.com/olimci/library/store"
     "github.com/olimci/sched"
     "log/slog"
     "net/http"
     "os"
-    "sync"
+    "strconv"
     "time"
 )
 
@@ -17,6 +22,17 @@ const (
     baseFileName = "logs/occupancy.csv"
     url          = "https://apps.dur.ac.uk/study-spaces/library/bill-bryson/occupancy/display?json&affluence"
     interval     = 30 * time.Second
+
+    defaultMetricsAddr = ":9090"
+    defaultAPIAddr     = ":8080"
+
+    retryInitialInterval = time.Second
+    retryMaxInterval     = 15 * time.Second
+    retryMaxElapsedTime  = 25 * time.Second
+
+    maxLogBytes   = 64 * 1024 * 1024
+    maxLogAge     = 90 * 24 * time.Hour
+    maxLogBackups = 52
 )
 
 type LevelData struct {
@@ -31,9 +47,58 @@ type Response struct {
     Affluence map[string]LevelData `json:"affluence"`
 }
 
-var lock sync.RWMutex
+var logRotator = rotator.New(rotator.Config{
+    Path:       baseFileName,
+    MaxBytes:   maxLogBytes,
+    Weekday:    time.Monday,
+    MaxAge:     maxLogAge,
+    MaxBackups: maxLogBackups,
+})
+
+var notifier *notify.Notifier
+
+var dataStore store.Store
 
 func main() {
+    var err error
+    dataStore, err = store.New(storeConfig())
+    if err != nil {
+        slog.Error("failed to open store", "err", err)
+        return
+    }
+    defer dataStore.Close()
+
+    metricsAddr := defaultMetricsAddr
+    if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+        metricsAddr = addr
+    }
+
+    go func() {
+        if err := metrics.Listen(metricsAddr); err != nil {
+            slog.Error("failed to serve metrics", "err", err)
+        }
+    }()
+
+    apiAddr := defaultAPIAddr
+    if addr := os.Getenv("API_ADDR"); addr != "" {
+        apiAddr = addr
+    }
+
+    go func() {
+        if err := api.Listen(apiAddr, dataStore); err != nil {
+            slog.Error("failed to serve api", "err", err)
+        }
+    }()
+
+    if path := os.Getenv("NOTIFY_CONFIG"); path != "" {
+        cfg, err := notify.LoadConfig(path, os.ReadFile)
+        if err != nil {
+            slog.Error("failed to load notify config", "err", err)
+        } else if notifier, err = notify.NewNotifier(cfg); err != nil {
+            slog.Error("failed to build notifier", "err", err)
+        }
+    }
+
     s := sched.New()
 
     if err := s.Start(context.Background()); err != nil {
@@ -43,134 +108,207 @@ func main() {
 
     s.Add(sched.Every(getOccupancy, interval))
 
-    // rotate every monday at 00:00:00
-    s.Add(sched.Weekday(rotateLog, nil, 0, 0, 0, nil))
+    // checked every tick so both size- and weekday-based rotation fire promptly
+    s.Add(sched.Every(logRotator.Check, interval))
 
     s.Wait()
 }
 
+// storeConfig builds the store.Config from the environment, defaulting to
+// the original CSV backend so existing deployments keep working unchanged.
+func storeConfig() store.Config {
+    return store.Config{
+        Backend: os.Getenv("STORE_BACKEND"),
+        Path:    baseFileName,
+        DSN:     os.Getenv("STORE_DSN"),
+        Dir:     os.Getenv("STORE_DIR"),
+    }
+}
+
 func getOccupancy(ctx context.Context) error {
     client := new(http.Client)
-    req, err := http.NewRequest("GET", url, nil)
-    if err != nil {
-        slog.Error("failed to create request", "err", err)
-        return err
-    }
-    req.Header.Set("User-Agent", "oli-bot/1.0 (+https://oli.mcinnes.cc)")
 
-    resp, err := client.Do(req)
-    if err != nil {
-        slog.Error("failed to send request", "err", err)
-        return err
+    var data Response
+    fetch := func() error {
+        req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+        if err != nil {
+            return backoff.Permanent(err)
+        }
+        req.Header.Set("User-Agent", "oli-bot/1.0 (+https://oli.mcinnes.cc)")
+
+        resp, err := client.Do(req)
+        if err != nil {
+            return err
+        }
+        defer resp.Body.Close()
+
+        if err := checkStatus(ctx, resp); err != nil {
+            return err
+        }
+
+        data = Response{}
+        if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+            return err
+        }
+
+        return nil
     }
-    defer resp.Body.Close()
 
-    if resp.StatusCode != http.StatusOK {
-        slog.Error("bad status code", "code", resp.StatusCode)
-        return fmt.Errorf("bad status code: %d", resp.StatusCode)
+    b := backoff.WithContext(newBackOff(), ctx)
+    notifyRetry := func(err error, wait time.Duration) {
+        slog.Warn("retrying occupancy request", "err", err, "wait", wait)
     }
 
-    var data Response
-    if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-        slog.Error("failed to decode response", "err", err)
+    if err := backoff.RetryNotify(fetch, b, notifyRetry); err != nil {
+        slog.Error("failed to fetch occupancy data", "err", err)
+        notePollResult(ctx, err)
         return err
     }
+    notePollResult(ctx, nil)
+
+    updateMetrics(data)
+    api.SetCurrent(data, time.Now())
+    checkThresholds(ctx, data)
 
-    lock.RLock()
-    defer lock.RUnlock()
+    release := logRotator.Acquire()
+    defer release()
 
-    return writeCSV(baseFileName, data)
+    return dataStore.Write(ctx, time.Now(), toOccupancy(data))
 }
 
-func writeCSV(path string, data Response) error {
-    fileExists := fileExists(path)
+func toOccupancy(data Response) store.Occupancy {
+    levels := make(map[string]store.LevelData, len(data.Affluence))
+    for level, lv := range data.Affluence {
+        levels[level] = store.LevelData{
+            Free:    lv.Free,
+            Total:   lv.Total,
+            FreePct: lv.FreePercentage,
+            UsedPct: lv.UsedPercentage,
+        }
+    }
 
-    file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-    if err != nil {
-        slog.Error("failed to open CSV file", "err", err)
-        return err
+    return store.Occupancy{
+        Telepen: store.LevelData{
+            Free:    data.Telepen.Free,
+            Total:   data.Telepen.Total,
+            FreePct: data.Telepen.FreePercentage,
+            UsedPct: data.Telepen.UsedPercentage,
+        },
+        Levels: levels,
     }
-    defer file.Close()
+}
 
-    writer := csv.NewWriter(file)
-    defer writer.Flush()
+func newBackOff() *backoff.ExponentialBackOff {
+    b := backoff.NewExponentialBackOff()
+    b.InitialInterval = retryInitialInterval
+    b.Multiplier = 2
+    b.MaxInterval = retryMaxInterval
+    b.MaxElapsedTime = retryMaxElapsedTime
+    return b
+}
 
-    if !fileExists {
-        header := []string{
-            "timestamp",
-            "telepen_free", "telepen_total", "telepen_free_pct", "telepen_used_pct",
-        }
-        for level := range data.Affluence {
-            header = append(header,
-                fmt.Sprintf("%s_free", level),
-                fmt.Sprintf("%s_total", level),
-                fmt.Sprintf("%s_free_pct", level),
-                fmt.Sprintf("%s_used_pct", level),
-            )
-        }
-        if err := writer.Write(header); err != nil {
-            slog.Error("failed to write CSV header", "err", err)
-            return err
-        }
+// checkStatus classifies the response status code: nil on success, a
+// backoff.Permanent error on non-retryable 4xx, and a plain (retryable)
+// error otherwise. Retry-After is honored on 429/503 by sleeping out the
+// requested wait (bounded by ctx and capped at retryMaxInterval, so a large
+// or hostile header can't stall the shared scheduler task past what
+// newBackOff's own MaxElapsedTime budgets for a retry) before returning the
+// retryable error.
+func checkStatus(ctx context.Context, resp *http.Response) error {
+    if resp.StatusCode == http.StatusOK {
+        return nil
     }
 
-    row := []string{
-        time.Now().Format(time.RFC3339),
-        fmt.Sprint(data.Telepen.Free),
-        fmt.Sprint(data.Telepen.Total),
-        fmt.Sprintf("%.1f", data.Telepen.FreePercentage),
-        fmt.Sprintf("%.1f", data.Telepen.UsedPercentage),
-    }
-    for _, level := range []string{"Level1", "Level2e", "Level3e", "Level3nsw", "Level4e", "Level4nsw"} {
-        lv := data.Affluence[level]
-        row = append(row,
-            fmt.Sprint(lv.Free),
-            fmt.Sprint(lv.Total),
-            fmt.Sprintf("%.1f", lv.FreePercentage),
-            fmt.Sprintf("%.1f", lv.UsedPercentage),
-        )
-    }
+    err := fmt.Errorf("bad status code: %d", resp.StatusCode)
 
-    if err := writer.Write(row); err != nil {
-        slog.Error("failed to write CSV row", "err", err)
+    if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+        if seconds, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+            wait := time.Duration(seconds) * time.Second
+            if wait > retryMaxInterval {
+                wait = retryMaxInterval
+            }
+            sleep(ctx, wait)
+        }
         return err
     }
 
-    slog.Info("logged occupancy data", "timestamp", row[0])
-    return nil
-}
+    if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout {
+        return backoff.Permanent(err)
+    }
 
-func rotateLog(context.Context) error {
-    lock.Lock()
-    defer lock.Unlock()
+    return err
+}
 
-    // use timestamp in filename e.g., occupancy_2025-05-19.csv
-    suffix := time.Now().Format("2006-01-02")
-    newName := fmt.Sprintf("occupancy_%s.csv", suffix)
+// sleep waits out d, returning early if ctx is done.
+func sleep(ctx context.Context, d time.Duration) {
+    timer := time.NewTimer(d)
+    defer timer.Stop()
 
-    // skip if file doesn't exist
-    if !fileExists(baseFileName) {
-        slog.Info("no file to rotate")
-        return nil
+    select {
+    case <-timer.C:
+    case <-ctx.Done():
     }
+}
 
-    // avoid overwrite
-    if fileExists(newName) {
-        slog.Warn("rotation target already exists", "target", newName)
-        return nil
+func retryAfter(header string) (int, bool) {
+    if header == "" {
+        return 0, false
     }
 
-    err := os.Rename(baseFileName, newName)
+    seconds, err := strconv.Atoi(header)
     if err != nil {
-        slog.Error("failed to rotate log", "err", err)
-        return err
+        return 0, false
+    }
+
+    return seconds, true
+}
+
+func notePollResult(ctx context.Context, err error) {
+    if notifier == nil {
+        return
+    }
+    notifier.NotePollResult(ctx, err)
+}
+
+func checkThresholds(ctx context.Context, data Response) {
+    if notifier == nil {
+        return
     }
 
-    slog.Info("rotated log file", "new", newName)
-    return nil
+    notifier.CheckLevel(ctx, "", libraryUsedPct(data))
+    for level, lv := range data.Affluence {
+        notifier.CheckLevel(ctx, level, lv.UsedPercentage)
+    }
+}
+
+// libraryUsedPct aggregates the whole-library used percentage as
+// used-seats/total-seats across every level in data.Affluence, weighting
+// each level by its capacity rather than averaging its percentages. Telepen
+// is a separate entrance-gate counter, not a level, and isn't included.
+func libraryUsedPct(data Response) float64 {
+    var total, free int
+    for _, lv := range data.Affluence {
+        total += lv.Total
+        free += lv.Free
+    }
+    if total == 0 {
+        return 0
+    }
+    return float64(total-free) / float64(total) * 100
 }
 
-func fileExists(path string) bool {
-    _, err := os.Stat(path)
-    return err == nil
+func updateMetrics(data Response) {
+    levels := make(map[string]metrics.LevelSample, len(data.Affluence))
+    for level, lv := range data.Affluence {
+        levels[level] = metrics.LevelSample{
+            Free:    lv.Free,
+            Total:   lv.Total,
+            UsedPct: lv.UsedPercentage,
+        }
+    }
+
+    metrics.Update(metrics.LevelSample{
+        Free:  data.Telepen.Free,
+        Total: data.Telepen.Total,
+    }, levels)
 }
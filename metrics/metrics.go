@@ -0,0 +1,61 @@
+// Package metrics exposes live occupancy data as Prometheus gauges, served
+// over HTTP so Grafana / Alertmanager can scrape it instead of parsing CSVs.
+package metrics
+
+import (
+    "net/http"
+
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promauto"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+    free = promauto.NewGaugeVec(prometheus.GaugeOpts{
+        Name: "library_free",
+        Help: "Number of free spaces on a level.",
+    }, []string{"level"})
+
+    total = promauto.NewGaugeVec(prometheus.GaugeOpts{
+        Name: "library_total",
+        Help: "Total number of spaces on a level.",
+    }, []string{"level"})
+
+    usedPct = promauto.NewGaugeVec(prometheus.GaugeOpts{
+        Name: "library_used_pct",
+        Help: "Percentage of spaces in use on a level.",
+    }, []string{"level"})
+
+    telepenFree = promauto.NewGauge(prometheus.GaugeOpts{
+        Name: "library_telepen_free",
+        Help: "Number of free Telepen-gated spaces.",
+    })
+)
+
+// LevelSample is the subset of level occupancy data the metrics package
+// needs to update its gauges.
+type LevelSample struct {
+    Free    int
+    Total   int
+    UsedPct float64
+}
+
+// Update refreshes the occupancy gauges from a freshly decoded response.
+func Update(telepen LevelSample, levels map[string]LevelSample) {
+    telepenFree.Set(float64(telepen.Free))
+
+    for level, lv := range levels {
+        free.WithLabelValues(level).Set(float64(lv.Free))
+        total.WithLabelValues(level).Set(float64(lv.Total))
+        usedPct.WithLabelValues(level).Set(lv.UsedPct)
+    }
+}
+
+// Listen starts the /metrics HTTP endpoint on addr. It blocks until the
+// server stops, so callers should run it in its own goroutine.
+func Listen(addr string) error {
+    mux := http.NewServeMux()
+    mux.Handle("/metrics", promhttp.Handler())
+
+    return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,363 @@
+// Package notify sends threshold-crossing occupancy events to pluggable
+// sinks (Telegram, generic webhooks), debounced so a flapping value doesn't
+// spam the configured channels.
+package notify
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+
+    "gopkg.in/yaml.v3"
+)
+
+// Sink delivers a single notification event.
+type Sink interface {
+    Send(ctx context.Context, event Event) error
+}
+
+// Event describes a single threshold crossing or failure condition.
+type Event struct {
+    Level     string // empty for library-wide events
+    Message   string
+    UsedPct   float64
+    Timestamp time.Time
+}
+
+// Duration is a time.Duration that unmarshals the same human-readable
+// syntax ("15m", "90s") from both YAML and JSON, since encoding/json (unlike
+// yaml.v3) has no built-in support for parsing time.Duration from a string.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+    var v any
+    if err := json.Unmarshal(data, &v); err != nil {
+        return err
+    }
+
+    switch value := v.(type) {
+    case string:
+        parsed, err := time.ParseDuration(value)
+        if err != nil {
+            return fmt.Errorf("invalid duration %q: %w", value, err)
+        }
+        *d = Duration(parsed)
+    case float64:
+        *d = Duration(value)
+    default:
+        return fmt.Errorf("invalid duration: %v", v)
+    }
+
+    return nil
+}
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+    // Try the integer form first: yaml.v3 decodes any scalar into a string,
+    // so a bare nanosecond count would otherwise reach ParseDuration.
+    var n int64
+    if err := value.Decode(&n); err == nil {
+        *d = Duration(n)
+        return nil
+    }
+
+    var s string
+    if err := value.Decode(&s); err != nil {
+        return fmt.Errorf("invalid duration: %s", value.Value)
+    }
+    parsed, err := time.ParseDuration(s)
+    if err != nil {
+        return fmt.Errorf("invalid duration %q: %w", s, err)
+    }
+    *d = Duration(parsed)
+    return nil
+}
+
+// Config is the on-disk notifier configuration, loaded from YAML or JSON.
+type Config struct {
+    Sinks      []SinkConfig      `yaml:"sinks" json:"sinks"`
+    Thresholds []ThresholdConfig `yaml:"thresholds" json:"thresholds"`
+
+    // Debounce is the minimum time between repeat notifications for the
+    // same level/direction before the value has moved back through the
+    // hysteresis band. Accepts "15m"-style strings or a nanosecond count in
+    // both YAML and JSON. Defaults to 15 minutes.
+    Debounce Duration `yaml:"debounce" json:"debounce"`
+
+    // Hysteresis is the band a value must cross back through for a repeat
+    // notification to be allowed before Debounce has elapsed.
+    // Defaults to 5 (percentage points).
+    Hysteresis float64 `yaml:"hysteresis" json:"hysteresis"`
+
+    // FailureStreak is the number of consecutive poll failures that
+    // triggers a "collector is failing" notification. Zero disables it.
+    FailureStreak int `yaml:"failure_streak" json:"failure_streak"`
+}
+
+// SinkConfig selects and configures one notification sink.
+type SinkConfig struct {
+    Type     string          `yaml:"type" json:"type"` // "telegram" or "webhook"
+    Telegram *TelegramConfig `yaml:"telegram,omitempty" json:"telegram,omitempty"`
+    Webhook  *WebhookConfig  `yaml:"webhook,omitempty" json:"webhook,omitempty"`
+}
+
+// ThresholdConfig fires when a level (or the whole library, if Level is
+// empty) crosses UsedPctAbove upward or UsedPctBelow downward.
+type ThresholdConfig struct {
+    Level        string   `yaml:"level" json:"level"`
+    UsedPctAbove *float64 `yaml:"used_pct_above,omitempty" json:"used_pct_above,omitempty"`
+    UsedPctBelow *float64 `yaml:"used_pct_below,omitempty" json:"used_pct_below,omitempty"`
+}
+
+// LoadConfig reads a notifier Config from a YAML or JSON file, chosen by
+// extension (".json" for JSON, anything else for YAML).
+func LoadConfig(path string, read func(string) ([]byte, error)) (*Config, error) {
+    data, err := read(path)
+    if err != nil {
+        return nil, fmt.Errorf("read notify config: %w", err)
+    }
+
+    var cfg Config
+    if strings.HasSuffix(path, ".json") {
+        err = json.Unmarshal(data, &cfg)
+    } else {
+        err = yaml.Unmarshal(data, &cfg)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("parse notify config: %w", err)
+    }
+
+    if cfg.Debounce == 0 {
+        cfg.Debounce = Duration(15 * time.Minute)
+    }
+    if cfg.Hysteresis == 0 {
+        cfg.Hysteresis = 5
+    }
+
+    return &cfg, nil
+}
+
+// Notifier evaluates thresholds against occupancy samples and dispatches
+// debounced events to the configured sinks.
+type Notifier struct {
+    cfg   Config
+    sinks []Sink
+
+    mu       sync.Mutex
+    lastSent map[string]time.Time
+    disarmed map[string]float64 // key -> usedPct at last trigger, present while the side still owes a hysteresis clear
+    failures int
+}
+
+// NewNotifier builds a Notifier from cfg, constructing a Sink for each
+// configured entry.
+func NewNotifier(cfg *Config) (*Notifier, error) {
+    n := &Notifier{
+        cfg:      *cfg,
+        lastSent: make(map[string]time.Time),
+        disarmed: make(map[string]float64),
+    }
+
+    for _, sc := range cfg.Sinks {
+        sink, err := buildSink(sc)
+        if err != nil {
+            return nil, err
+        }
+        n.sinks = append(n.sinks, sink)
+    }
+
+    return n, nil
+}
+
+func buildSink(sc SinkConfig) (Sink, error) {
+    switch sc.Type {
+    case "telegram":
+        if sc.Telegram == nil {
+            return nil, fmt.Errorf("telegram sink missing config")
+        }
+        return sc.Telegram, nil
+    case "webhook":
+        if sc.Webhook == nil {
+            return nil, fmt.Errorf("webhook sink missing config")
+        }
+        return sc.Webhook, nil
+    default:
+        return nil, fmt.Errorf("unknown sink type: %q", sc.Type)
+    }
+}
+
+// CheckLevel evaluates the configured thresholds for one level (or "" for
+// the whole library) against usedPct, dispatching a debounced Send to every
+// sink on each crossing.
+func (n *Notifier) CheckLevel(ctx context.Context, level string, usedPct float64) {
+    for _, t := range n.cfg.Thresholds {
+        if t.Level != level {
+            continue
+        }
+
+        if t.UsedPctAbove != nil && usedPct >= *t.UsedPctAbove {
+            n.fire(ctx, level, 1, usedPct, fmt.Sprintf("%s used %.1f%% (>= %.1f%%)", levelLabel(level), usedPct, *t.UsedPctAbove))
+        }
+        if t.UsedPctBelow != nil && usedPct <= *t.UsedPctBelow {
+            n.fire(ctx, level, -1, usedPct, fmt.Sprintf("%s used %.1f%% (<= %.1f%%)", levelLabel(level), usedPct, *t.UsedPctBelow))
+        }
+    }
+}
+
+// NotePollResult tracks consecutive poll failures, firing once the
+// configured streak length is reached.
+func (n *Notifier) NotePollResult(ctx context.Context, err error) {
+    n.mu.Lock()
+    if err == nil {
+        n.failures = 0
+        n.mu.Unlock()
+        return
+    }
+
+    n.failures++
+    failures := n.failures
+    n.mu.Unlock()
+
+    if n.cfg.FailureStreak > 0 && failures == n.cfg.FailureStreak {
+        n.send(ctx, Event{
+            Message:   fmt.Sprintf("collector has failed %d consecutive polls: %v", failures, err),
+            Timestamp: time.Now(),
+        })
+    }
+}
+
+// fire sends a threshold-crossing event unless it's still inside the
+// hysteresis band of the side's last trigger and Debounce hasn't elapsed
+// since the last send. Crossing back through the Hysteresis band clears
+// the side, so the next crossing always fires regardless of Debounce.
+func (n *Notifier) fire(ctx context.Context, level string, side int, usedPct float64, message string) {
+    key := fmt.Sprintf("%s:%d", level, side)
+
+    n.mu.Lock()
+    now := time.Now()
+    trigger, disarmed := n.disarmed[key]
+    if disarmed && clearedHysteresis(side, usedPct, trigger, n.cfg.Hysteresis) {
+        delete(n.disarmed, key)
+        disarmed = false
+    }
+
+    if disarmed {
+        if last, sent := n.lastSent[key]; sent && now.Sub(last) < time.Duration(n.cfg.Debounce) {
+            n.mu.Unlock()
+            return
+        }
+    }
+
+    n.lastSent[key] = now
+    n.disarmed[key] = usedPct
+    n.mu.Unlock()
+
+    n.send(ctx, Event{
+        Level:     level,
+        Message:   message,
+        UsedPct:   usedPct,
+        Timestamp: now,
+    })
+}
+
+// clearedHysteresis reports whether usedPct has moved back far enough from
+// trigger (the value that last fired this side) to re-arm it: at least
+// Hysteresis points below trigger for the above-threshold side, or above
+// for the below-threshold side.
+func clearedHysteresis(side int, usedPct, trigger, hysteresis float64) bool {
+    if side > 0 {
+        return usedPct <= trigger-hysteresis
+    }
+    return usedPct >= trigger+hysteresis
+}
+
+func (n *Notifier) send(ctx context.Context, event Event) {
+    for _, sink := range n.sinks {
+        if err := sink.Send(ctx, event); err != nil {
+            // Best-effort: one failing sink shouldn't stop the others.
+            continue
+        }
+    }
+}
+
+func levelLabel(level string) string {
+    if level == "" {
+        return "library"
+    }
+    return level
+}
+
+// TelegramConfig sends events via the Telegram Bot API.
+type TelegramConfig struct {
+    Token  string `yaml:"token" json:"token"`
+    ChatID string `yaml:"chat_id" json:"chat_id"`
+}
+
+func (c *TelegramConfig) Send(ctx context.Context, event Event) error {
+    endpoint := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", c.Token)
+
+    body, err := json.Marshal(map[string]string{
+        "chat_id": c.ChatID,
+        "text":    event.Message,
+    })
+    if err != nil {
+        return err
+    }
+
+    req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("telegram: bad status code: %d", resp.StatusCode)
+    }
+
+    return nil
+}
+
+// WebhookConfig posts events as JSON to a generic URL.
+type WebhookConfig struct {
+    URL string `yaml:"url" json:"url"`
+}
+
+func (c *WebhookConfig) Send(ctx context.Context, event Event) error {
+    if _, err := url.Parse(c.URL); err != nil {
+        return fmt.Errorf("webhook: invalid url: %w", err)
+    }
+
+    body, err := json.Marshal(event)
+    if err != nil {
+        return err
+    }
+
+    req, err := http.NewRequestWithContext(ctx, "POST", c.URL, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode >= 300 {
+        return fmt.Errorf("webhook: bad status code: %d", resp.StatusCode)
+    }
+
+    return nil
+}
@@ -0,0 +1,268 @@
+// Package rotator rotates the active occupancy CSV by size or on a weekly
+// schedule, compresses rotated files, and prunes old archives. It owns the
+// lock that serializes rotation against concurrent CSV appends.
+package rotator
+
+import (
+    "compress/gzip"
+    "context"
+    "fmt"
+    "io"
+    "log/slog"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Config controls when the active file rotates and how archives are kept.
+type Config struct {
+    // Path is the active CSV file, e.g. "logs/occupancy.csv".
+    Path string
+
+    // MaxBytes rotates the active file once it grows past this size.
+    // Zero disables size-based rotation.
+    MaxBytes int64
+
+    // Weekday, Hour, Min and Sec select the weekly time-based rotation
+    // slot, mirroring sched.Weekday's arguments.
+    Weekday time.Weekday
+    Hour    int
+    Min     int
+    Sec     int
+
+    // MaxAge deletes archives older than this. Zero disables pruning by age.
+    MaxAge time.Duration
+
+    // MaxBackups keeps only the N newest archives. Zero disables pruning by count.
+    MaxBackups int
+}
+
+// Rotator guards the active CSV file and rotates it into compressed,
+// retention-managed archives.
+type Rotator struct {
+    cfg         Config
+    mu          sync.RWMutex
+    lastRotated time.Time
+}
+
+// New builds a Rotator for cfg, seeding lastRotated from whatever rotation
+// history is already on disk so a process restart doesn't look like a full
+// week of elapsed time and fire a spurious weekday rotation.
+func New(cfg Config) *Rotator {
+    return &Rotator{cfg: cfg, lastRotated: initialLastRotated(cfg)}
+}
+
+// initialLastRotated recovers the last rotation time across restarts: the
+// newest archive's timestamp if any archives exist, falling back to the
+// active file's mtime, or the zero time if neither exists (nothing to lose
+// by rotating immediately).
+func initialLastRotated(cfg Config) time.Time {
+    if t, ok := lastArchiveTime(cfg.Path); ok {
+        return t
+    }
+    if info, err := os.Stat(cfg.Path); err == nil {
+        return info.ModTime()
+    }
+    return time.Time{}
+}
+
+// lastArchiveTime returns the newest timestamp encoded in path's rotated
+// archive filenames (active or pruned history aside), if any exist.
+func lastArchiveTime(path string) (time.Time, bool) {
+    archives, err := filepath.Glob(archiveGlob(path))
+    if err != nil || len(archives) == 0 {
+        return time.Time{}, false
+    }
+
+    base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+    var latest time.Time
+    found := false
+    for _, archive := range archives {
+        name := strings.TrimSuffix(filepath.Base(archive), ".csv.gz")
+        ts := strings.TrimPrefix(name, base+"_")
+
+        t, err := time.Parse("2006-01-02T15-04-05", ts)
+        if err != nil {
+            continue
+        }
+        if !found || t.After(latest) {
+            latest, found = t, true
+        }
+    }
+
+    return latest, found
+}
+
+// Acquire takes the read side of the rotation lock, blocking rotation until
+// release is called. Callers appending to the active CSV should hold this
+// for the duration of the write.
+func (r *Rotator) Acquire() (release func()) {
+    r.mu.RLock()
+    return r.mu.RUnlock
+}
+
+// Check rotates the active file if it has grown past MaxBytes or if the
+// configured weekly time slot has passed since the last rotation, then
+// compresses the result and prunes old archives. It is safe to call
+// frequently, e.g. on every scheduler tick.
+func (r *Rotator) Check(ctx context.Context) error {
+    r.mu.Lock()
+    rotated, archive, err := r.rotateLocked()
+    r.mu.Unlock()
+
+    if err != nil {
+        return err
+    }
+    if !rotated {
+        return nil
+    }
+
+    go r.finishRotation(archive)
+
+    return nil
+}
+
+func (r *Rotator) rotateLocked() (rotated bool, archive string, err error) {
+    if !fileExists(r.cfg.Path) {
+        return false, "", nil
+    }
+
+    due, err := r.dueLocked()
+    if err != nil {
+        return false, "", err
+    }
+    if !due {
+        return false, "", nil
+    }
+
+    archive = fmt.Sprintf("%s_%s.csv", strings.TrimSuffix(r.cfg.Path, filepath.Ext(r.cfg.Path)), time.Now().Format("2006-01-02T15-04-05"))
+    if fileExists(archive) {
+        return false, "", fmt.Errorf("rotation target already exists: %s", archive)
+    }
+
+    if err := os.Rename(r.cfg.Path, archive); err != nil {
+        return false, "", fmt.Errorf("rotate log: %w", err)
+    }
+
+    r.lastRotated = time.Now()
+    slog.Info("rotated log file", "new", archive)
+
+    return true, archive, nil
+}
+
+func (r *Rotator) dueLocked() (bool, error) {
+    if r.cfg.MaxBytes > 0 {
+        info, err := os.Stat(r.cfg.Path)
+        if err != nil {
+            return false, err
+        }
+        if info.Size() >= r.cfg.MaxBytes {
+            return true, nil
+        }
+    }
+
+    now := time.Now()
+    slot := time.Date(now.Year(), now.Month(), now.Day(), r.cfg.Hour, r.cfg.Min, r.cfg.Sec, 0, now.Location())
+    for slot.Weekday() != r.cfg.Weekday {
+        slot = slot.AddDate(0, 0, -1)
+    }
+
+    return !slot.Before(r.lastRotated) && !slot.After(now), nil
+}
+
+// finishRotation gzips the freshly rotated archive and prunes old ones. It
+// runs in the background so a slow compression pass never blocks a tick.
+func (r *Rotator) finishRotation(archive string) {
+    gzPath, err := gzipFile(archive)
+    if err != nil {
+        slog.Error("failed to compress rotated log", "file", archive, "err", err)
+        return
+    }
+
+    if err := os.Remove(archive); err != nil {
+        slog.Error("failed to remove uncompressed rotated log", "file", archive, "err", err)
+    }
+
+    if err := r.prune(); err != nil {
+        slog.Error("failed to prune rotated logs", "err", err)
+    }
+
+    slog.Info("compressed rotated log", "archive", gzPath)
+}
+
+func gzipFile(path string) (string, error) {
+    src, err := os.Open(path)
+    if err != nil {
+        return "", err
+    }
+    defer src.Close()
+
+    dstPath := path + ".gz"
+    dst, err := os.Create(dstPath)
+    if err != nil {
+        return "", err
+    }
+    defer dst.Close()
+
+    gw := gzip.NewWriter(dst)
+    if _, err := io.Copy(gw, src); err != nil {
+        gw.Close()
+        return "", err
+    }
+
+    return dstPath, gw.Close()
+}
+
+// prune enforces MaxAge and MaxBackups over the rotated archives, keeping
+// the active file untouched.
+func (r *Rotator) prune() error {
+    archives, err := filepath.Glob(archiveGlob(r.cfg.Path))
+    if err != nil {
+        return err
+    }
+
+    infos := make([]os.FileInfo, 0, len(archives))
+    for _, archive := range archives {
+        info, err := os.Stat(archive)
+        if err != nil {
+            continue
+        }
+        infos = append(infos, info)
+    }
+
+    sort.Slice(infos, func(i, j int) bool {
+        return infos[i].ModTime().After(infos[j].ModTime())
+    })
+
+    now := time.Now()
+    for i, info := range infos {
+        expired := r.cfg.MaxAge > 0 && now.Sub(info.ModTime()) > r.cfg.MaxAge
+        excess := r.cfg.MaxBackups > 0 && i >= r.cfg.MaxBackups
+
+        if !expired && !excess {
+            continue
+        }
+
+        path := filepath.Join(filepath.Dir(r.cfg.Path), info.Name())
+        if err := os.Remove(path); err != nil {
+            return err
+        }
+        slog.Info("pruned rotated log", "file", path)
+    }
+
+    return nil
+}
+
+func archiveGlob(path string) string {
+    base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+    return filepath.Join(filepath.Dir(path), base+"_*.csv.gz")
+}
+
+func fileExists(path string) bool {
+    _, err := os.Stat(path)
+    return err == nil
+}
@@ -0,0 +1,299 @@
+package store
+
+import (
+    "compress/gzip"
+    "context"
+    "encoding/csv"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// CSVStore is the original append-only CSV backend. The column order is
+// derived once from the first write and reused for every row afterwards,
+// so (unlike the old writeCSV) the header and the row can never drift out
+// of sync with each other.
+type CSVStore struct {
+    path string
+
+    mu     sync.Mutex
+    levels []string // fixed order, set on first write
+}
+
+// NewCSVStore opens (or prepares to create) the CSV file at path.
+func NewCSVStore(path string) (*CSVStore, error) {
+    return &CSVStore{path: path}, nil
+}
+
+func (s *CSVStore) Write(ctx context.Context, timestamp time.Time, data Occupancy) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.levels == nil {
+        s.levels = orderedLevels(data)
+    }
+
+    exists := fileExists(s.path)
+
+    file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+    if err != nil {
+        return fmt.Errorf("open csv: %w", err)
+    }
+    defer file.Close()
+
+    writer := csv.NewWriter(file)
+    defer writer.Flush()
+
+    if !exists {
+        if err := writer.Write(s.header()); err != nil {
+            return fmt.Errorf("write csv header: %w", err)
+        }
+    }
+
+    if err := writer.Write(s.row(timestamp, data)); err != nil {
+        return fmt.Errorf("write csv row: %w", err)
+    }
+
+    return nil
+}
+
+func (s *CSVStore) header() []string {
+    header := []string{"timestamp"}
+    header = append(header, levelColumns(TelepenLevel)...)
+    for _, level := range s.levels {
+        header = append(header, levelColumns(level)...)
+    }
+    return header
+}
+
+func (s *CSVStore) row(timestamp time.Time, data Occupancy) []string {
+    row := []string{timestamp.Format(time.RFC3339)}
+    row = append(row, levelValues(data.Telepen)...)
+    for _, level := range s.levels {
+        row = append(row, levelValues(data.Levels[level])...)
+    }
+    return row
+}
+
+func levelColumns(level string) []string {
+    return []string{
+        level + "_free", level + "_total", level + "_free_pct", level + "_used_pct",
+    }
+}
+
+func levelValues(lv LevelData) []string {
+    return []string{
+        strconv.Itoa(lv.Free),
+        strconv.Itoa(lv.Total),
+        strconv.FormatFloat(lv.FreePct, 'f', 1, 64),
+        strconv.FormatFloat(lv.UsedPct, 'f', 1, 64),
+    }
+}
+
+func (s *CSVStore) Query(ctx context.Context, q Query) ([]Sample, error) {
+    files, err := logFiles(s.path)
+    if err != nil {
+        return nil, err
+    }
+
+    from, to := q.From, q.To
+    if to.IsZero() {
+        to = time.Now()
+    }
+
+    var samples []Sample
+    for _, file := range files {
+        rows, err := readFileSamples(file, from, to, q.Level)
+        if err != nil {
+            return nil, err
+        }
+        samples = append(samples, rows...)
+    }
+
+    return samples, nil
+}
+
+// Levels lists the distinct level names, preferring the in-memory order
+// fixed on first write and falling back to the header of whichever log
+// file is found first, so it never has to read a single sample row.
+func (s *CSVStore) Levels(ctx context.Context) ([]string, error) {
+    s.mu.Lock()
+    if s.levels != nil {
+        levels := append([]string{TelepenLevel}, s.levels...)
+        s.mu.Unlock()
+        return levels, nil
+    }
+    s.mu.Unlock()
+
+    files, err := logFiles(s.path)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, file := range files {
+        header, err := readHeader(file)
+        if err != nil {
+            continue
+        }
+        return levelsFromHeader(header), nil
+    }
+
+    return nil, nil
+}
+
+func (s *CSVStore) Close() error {
+    return nil
+}
+
+// logFiles returns every occupancy CSV for path, active and rotated,
+// compressed or not, alike.
+func logFiles(path string) ([]string, error) {
+    dir := filepath.Dir(path)
+    base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+    plain, err := filepath.Glob(filepath.Join(dir, base+"*.csv"))
+    if err != nil {
+        return nil, err
+    }
+
+    gzipped, err := filepath.Glob(filepath.Join(dir, base+"*.csv.gz"))
+    if err != nil {
+        return nil, err
+    }
+
+    return append(plain, gzipped...), nil
+}
+
+// openCSV opens path for reading, transparently decompressing it if it's a
+// rotated .gz archive. The caller must close the returned closer.
+func openCSV(path string) (io.Reader, io.Closer, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    if !strings.HasSuffix(path, ".gz") {
+        return file, file, nil
+    }
+
+    gzr, err := gzip.NewReader(file)
+    if err != nil {
+        file.Close()
+        return nil, nil, fmt.Errorf("open gzip: %w", err)
+    }
+
+    return gzr, multiCloser{gzr, file}, nil
+}
+
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() error {
+    for _, c := range m {
+        if err := c.Close(); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func readHeader(path string) ([]string, error) {
+    r, closer, err := openCSV(path)
+    if err != nil {
+        return nil, err
+    }
+    defer closer.Close()
+
+    return csv.NewReader(r).Read()
+}
+
+func levelsFromHeader(header []string) []string {
+    var levels []string
+    for i := 1; i+3 < len(header); i += 4 {
+        levels = append(levels, strings.TrimSuffix(header[i], "_free"))
+    }
+    return levels
+}
+
+func readFileSamples(path string, from, to time.Time, level string) ([]Sample, error) {
+    r, closer, err := openCSV(path)
+    if err != nil {
+        return nil, err
+    }
+    defer closer.Close()
+
+    reader := csv.NewReader(r)
+
+    header, err := reader.Read()
+    if err != nil {
+        return nil, err
+    }
+
+    var samples []Sample
+    for {
+        row, err := reader.Read()
+        if err != nil {
+            break
+        }
+
+        ts, err := time.Parse(time.RFC3339, row[0])
+        if err != nil || ts.Before(from) || ts.After(to) {
+            continue
+        }
+
+        for i := 1; i+3 < len(header); i += 4 {
+            name := strings.TrimSuffix(header[i], "_free")
+            if level != "" && level != name {
+                continue
+            }
+
+            sample, err := parseSample(ts, name, row[i:i+4])
+            if err != nil {
+                continue
+            }
+            samples = append(samples, sample)
+        }
+    }
+
+    return samples, nil
+}
+
+func parseSample(ts time.Time, level string, cols []string) (Sample, error) {
+    free, err := strconv.Atoi(cols[0])
+    if err != nil {
+        return Sample{}, err
+    }
+
+    total, err := strconv.Atoi(cols[1])
+    if err != nil {
+        return Sample{}, err
+    }
+
+    freePct, err := strconv.ParseFloat(cols[2], 64)
+    if err != nil {
+        return Sample{}, err
+    }
+
+    usedPct, err := strconv.ParseFloat(cols[3], 64)
+    if err != nil {
+        return Sample{}, err
+    }
+
+    return Sample{
+        Timestamp: ts,
+        Level:     level,
+        Free:      free,
+        Total:     total,
+        FreePct:   freePct,
+        UsedPct:   usedPct,
+    }, nil
+}
+
+func fileExists(path string) bool {
+    _, err := os.Stat(path)
+    return err == nil
+}
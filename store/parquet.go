@@ -0,0 +1,241 @@
+package store
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/parquet-go/parquet-go"
+)
+
+// parquetRow is the on-disk row shape for ParquetStore.
+type parquetRow struct {
+    Timestamp int64   `parquet:"timestamp"`
+    Level     string  `parquet:"level"`
+    Free      int32   `parquet:"free"`
+    Total     int32   `parquet:"total"`
+    FreePct   float64 `parquet:"free_pct"`
+    UsedPct   float64 `parquet:"used_pct"`
+}
+
+// ParquetStore appends samples to a daily-rolling Parquet file, one file
+// per UTC day under dir (e.g. "occupancy_2025-05-19.parquet"). Files are
+// append-only for the day, then left immutable once the day rolls over.
+type ParquetStore struct {
+    dir string
+
+    mu      sync.Mutex
+    day     string
+    path    string
+    file    *os.File
+    writer  *parquet.GenericWriter[parquetRow]
+    pending []parquetRow // rows written to the active (still-open) file, mirrored here since its footer isn't flushed until roll/close
+}
+
+// NewParquetStore prepares a ParquetStore writing into dir.
+func NewParquetStore(dir string) (*ParquetStore, error) {
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return nil, fmt.Errorf("create parquet dir: %w", err)
+    }
+    return &ParquetStore{dir: dir}, nil
+}
+
+func (s *ParquetStore) Write(ctx context.Context, timestamp time.Time, data Occupancy) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if err := s.rollLocked(timestamp); err != nil {
+        return err
+    }
+
+    rows := []parquetRow{rowFor(timestamp, TelepenLevel, data.Telepen)}
+    for level, lv := range data.Levels {
+        rows = append(rows, rowFor(timestamp, level, lv))
+    }
+
+    if _, err := s.writer.Write(rows); err != nil {
+        return fmt.Errorf("write parquet rows: %w", err)
+    }
+
+    s.pending = append(s.pending, rows...)
+
+    return nil
+}
+
+func rowFor(timestamp time.Time, level string, lv LevelData) parquetRow {
+    return parquetRow{
+        Timestamp: timestamp.Unix(),
+        Level:     level,
+        Free:      int32(lv.Free),
+        Total:     int32(lv.Total),
+        FreePct:   lv.FreePct,
+        UsedPct:   lv.UsedPct,
+    }
+}
+
+// rollLocked closes the previous day's file (flushing its footer) and opens
+// a fresh one whenever the UTC day changes.
+func (s *ParquetStore) rollLocked(timestamp time.Time) error {
+    day := timestamp.UTC().Format("2006-01-02")
+    if day == s.day && s.writer != nil {
+        return nil
+    }
+
+    if err := s.closeLocked(); err != nil {
+        return err
+    }
+
+    path := filepath.Join(s.dir, fmt.Sprintf("occupancy_%s.parquet", day))
+    file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+    if err != nil {
+        return fmt.Errorf("open parquet file: %w", err)
+    }
+
+    s.file = file
+    s.writer = parquet.NewGenericWriter[parquetRow](file)
+    s.day = day
+    s.path = path
+    s.pending = nil
+
+    return nil
+}
+
+func (s *ParquetStore) closeLocked() error {
+    if s.writer == nil {
+        return nil
+    }
+
+    if err := s.writer.Close(); err != nil {
+        return fmt.Errorf("close parquet writer: %w", err)
+    }
+    if err := s.file.Close(); err != nil {
+        return fmt.Errorf("close parquet file: %w", err)
+    }
+
+    s.writer = nil
+    s.file = nil
+    s.path = ""
+    s.pending = nil
+    return nil
+}
+
+func (s *ParquetStore) Query(ctx context.Context, q Query) ([]Sample, error) {
+    files, err := filepath.Glob(filepath.Join(s.dir, "occupancy_*.parquet"))
+    if err != nil {
+        return nil, err
+    }
+
+    to := q.To
+    if to.IsZero() {
+        to = time.Now()
+    }
+
+    activePath, pending := s.activeLocked()
+
+    var samples []Sample
+    for _, file := range files {
+        if file == activePath {
+            samples = append(samples, filterRows(pending, q.From, to, q.Level)...)
+            continue
+        }
+
+        rows, err := readParquetFile(file, q.From, to, q.Level)
+        if err != nil {
+            return nil, err
+        }
+        samples = append(samples, rows...)
+    }
+
+    return samples, nil
+}
+
+// activeLocked snapshots the path and not-yet-flushed rows of today's still-open
+// file, since its footer isn't written (and so isn't readable) until it's rolled or closed.
+func (s *ParquetStore) activeLocked() (path string, pending []parquetRow) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return s.path, append([]parquetRow(nil), s.pending...)
+}
+
+func filterRows(rows []parquetRow, from, to time.Time, level string) []Sample {
+    var samples []Sample
+    for _, row := range rows {
+        ts := time.Unix(row.Timestamp, 0).UTC()
+        if ts.Before(from) || ts.After(to) {
+            continue
+        }
+        if level != "" && row.Level != level {
+            continue
+        }
+
+        samples = append(samples, Sample{
+            Timestamp: ts,
+            Level:     row.Level,
+            Free:      int(row.Free),
+            Total:     int(row.Total),
+            FreePct:   row.FreePct,
+            UsedPct:   row.UsedPct,
+        })
+    }
+
+    return samples
+}
+
+func readParquetFile(path string, from, to time.Time, level string) ([]Sample, error) {
+    rows, err := parquet.ReadFile[parquetRow](path)
+    if err != nil {
+        return nil, fmt.Errorf("read parquet file: %w", err)
+    }
+
+    return filterRows(rows, from, to, level), nil
+}
+
+// Levels lists the distinct level names, reading only the active day's
+// pending rows when available, or otherwise the single most recent
+// completed file, rather than every archive the way Query would.
+func (s *ParquetStore) Levels(ctx context.Context) ([]string, error) {
+    _, pending := s.activeLocked()
+    if len(pending) > 0 {
+        return uniqueLevels(pending), nil
+    }
+
+    files, err := filepath.Glob(filepath.Join(s.dir, "occupancy_*.parquet"))
+    if err != nil {
+        return nil, err
+    }
+    if len(files) == 0 {
+        return nil, nil
+    }
+
+    sort.Strings(files)
+    rows, err := parquet.ReadFile[parquetRow](files[len(files)-1])
+    if err != nil {
+        return nil, fmt.Errorf("read parquet file: %w", err)
+    }
+
+    return uniqueLevels(rows), nil
+}
+
+func uniqueLevels(rows []parquetRow) []string {
+    seen := make(map[string]bool)
+    var levels []string
+    for _, row := range rows {
+        if !seen[row.Level] {
+            seen[row.Level] = true
+            levels = append(levels, row.Level)
+        }
+    }
+    return levels
+}
+
+func (s *ParquetStore) Close() error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return s.closeLocked()
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "github.com/jackc/pgx/v5"
+    "github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresStore persists samples to Postgres via pgx.
+type PostgresStore struct {
+    pool *pgxpool.Pool
+}
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS samples (
+    timestamp timestamptz NOT NULL,
+    level     text NOT NULL,
+    free      integer NOT NULL,
+    total     integer NOT NULL,
+    free_pct  double precision NOT NULL,
+    used_pct  double precision NOT NULL
+);
+CREATE INDEX IF NOT EXISTS samples_timestamp_idx ON samples (timestamp);
+CREATE INDEX IF NOT EXISTS samples_level_idx ON samples (level);
+`
+
+// NewPostgresStore connects to Postgres using dsn and ensures the samples
+// table exists.
+func NewPostgresStore(dsn string) (*PostgresStore, error) {
+    pool, err := pgxpool.New(context.Background(), dsn)
+    if err != nil {
+        return nil, fmt.Errorf("connect postgres: %w", err)
+    }
+
+    if _, err := pool.Exec(context.Background(), postgresSchema); err != nil {
+        pool.Close()
+        return nil, fmt.Errorf("migrate postgres: %w", err)
+    }
+
+    return &PostgresStore{pool: pool}, nil
+}
+
+func (s *PostgresStore) Write(ctx context.Context, timestamp time.Time, data Occupancy) error {
+    tx, err := s.pool.Begin(ctx)
+    if err != nil {
+        return fmt.Errorf("begin tx: %w", err)
+    }
+    defer tx.Rollback(ctx)
+
+    if err := s.insertSample(ctx, tx, timestamp, TelepenLevel, data.Telepen); err != nil {
+        return err
+    }
+
+    for level, lv := range data.Levels {
+        if err := s.insertSample(ctx, tx, timestamp, level, lv); err != nil {
+            return err
+        }
+    }
+
+    return tx.Commit(ctx)
+}
+
+func (s *PostgresStore) insertSample(ctx context.Context, tx pgx.Tx, timestamp time.Time, level string, lv LevelData) error {
+    _, err := tx.Exec(ctx,
+        `INSERT INTO samples (timestamp, level, free, total, free_pct, used_pct) VALUES ($1, $2, $3, $4, $5, $6)`,
+        timestamp, level, lv.Free, lv.Total, lv.FreePct, lv.UsedPct,
+    )
+    if err != nil {
+        return fmt.Errorf("insert sample: %w", err)
+    }
+    return nil
+}
+
+func (s *PostgresStore) Query(ctx context.Context, q Query) ([]Sample, error) {
+    to := q.To
+    if to.IsZero() {
+        to = time.Now()
+    }
+
+    query := `SELECT timestamp, level, free, total, free_pct, used_pct FROM samples WHERE timestamp BETWEEN $1 AND $2`
+    args := []any{q.From, to}
+
+    if q.Level != "" {
+        query += ` AND level = $3`
+        args = append(args, q.Level)
+    }
+
+    rows, err := s.pool.Query(ctx, query, args...)
+    if err != nil {
+        return nil, fmt.Errorf("query samples: %w", err)
+    }
+    defer rows.Close()
+
+    var samples []Sample
+    for rows.Next() {
+        var sample Sample
+        if err := rows.Scan(&sample.Timestamp, &sample.Level, &sample.Free, &sample.Total, &sample.FreePct, &sample.UsedPct); err != nil {
+            return nil, fmt.Errorf("scan sample: %w", err)
+        }
+        samples = append(samples, sample)
+    }
+
+    return samples, rows.Err()
+}
+
+// Levels lists the distinct level names via SELECT DISTINCT, instead of
+// pulling every sample back the way Query would.
+func (s *PostgresStore) Levels(ctx context.Context) ([]string, error) {
+    rows, err := s.pool.Query(ctx, `SELECT DISTINCT level FROM samples`)
+    if err != nil {
+        return nil, fmt.Errorf("query levels: %w", err)
+    }
+    defer rows.Close()
+
+    var levels []string
+    for rows.Next() {
+        var level string
+        if err := rows.Scan(&level); err != nil {
+            return nil, fmt.Errorf("scan level: %w", err)
+        }
+        levels = append(levels, level)
+    }
+
+    return levels, rows.Err()
+}
+
+func (s *PostgresStore) Close() error {
+    s.pool.Close()
+    return nil
+}
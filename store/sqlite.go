@@ -0,0 +1,140 @@
+package store
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "time"
+
+    _ "modernc.org/sqlite"
+)
+
+// SQLiteStore persists samples to a SQLite database, using the cgo-free
+// modernc.org/sqlite driver.
+type SQLiteStore struct {
+    db *sql.DB
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS samples (
+    timestamp TEXT NOT NULL,
+    level     TEXT NOT NULL,
+    free      INTEGER NOT NULL,
+    total     INTEGER NOT NULL,
+    free_pct  REAL NOT NULL,
+    used_pct  REAL NOT NULL
+);
+CREATE INDEX IF NOT EXISTS samples_timestamp_idx ON samples (timestamp);
+CREATE INDEX IF NOT EXISTS samples_level_idx ON samples (level);
+`
+
+// NewSQLiteStore opens (creating if needed) the SQLite database at dsn.
+func NewSQLiteStore(dsn string) (*SQLiteStore, error) {
+    db, err := sql.Open("sqlite", dsn)
+    if err != nil {
+        return nil, fmt.Errorf("open sqlite: %w", err)
+    }
+
+    if _, err := db.Exec(sqliteSchema); err != nil {
+        db.Close()
+        return nil, fmt.Errorf("migrate sqlite: %w", err)
+    }
+
+    return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Write(ctx context.Context, timestamp time.Time, data Occupancy) error {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return fmt.Errorf("begin tx: %w", err)
+    }
+    defer tx.Rollback()
+
+    if err := insertSample(ctx, tx, timestamp, TelepenLevel, data.Telepen); err != nil {
+        return err
+    }
+
+    for level, lv := range data.Levels {
+        if err := insertSample(ctx, tx, timestamp, level, lv); err != nil {
+            return err
+        }
+    }
+
+    return tx.Commit()
+}
+
+func insertSample(ctx context.Context, tx *sql.Tx, timestamp time.Time, level string, lv LevelData) error {
+    _, err := tx.ExecContext(ctx,
+        `INSERT INTO samples (timestamp, level, free, total, free_pct, used_pct) VALUES (?, ?, ?, ?, ?, ?)`,
+        timestamp.Format(time.RFC3339), level, lv.Free, lv.Total, lv.FreePct, lv.UsedPct,
+    )
+    if err != nil {
+        return fmt.Errorf("insert sample: %w", err)
+    }
+    return nil
+}
+
+func (s *SQLiteStore) Query(ctx context.Context, q Query) ([]Sample, error) {
+    to := q.To
+    if to.IsZero() {
+        to = time.Now()
+    }
+
+    query := `SELECT timestamp, level, free, total, free_pct, used_pct FROM samples WHERE timestamp BETWEEN ? AND ?`
+    args := []any{q.From.Format(time.RFC3339), to.Format(time.RFC3339)}
+
+    if q.Level != "" {
+        query += ` AND level = ?`
+        args = append(args, q.Level)
+    }
+
+    rows, err := s.db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, fmt.Errorf("query samples: %w", err)
+    }
+    defer rows.Close()
+
+    var samples []Sample
+    for rows.Next() {
+        var sample Sample
+        var timestamp string
+
+        if err := rows.Scan(&timestamp, &sample.Level, &sample.Free, &sample.Total, &sample.FreePct, &sample.UsedPct); err != nil {
+            return nil, fmt.Errorf("scan sample: %w", err)
+        }
+
+        sample.Timestamp, err = time.Parse(time.RFC3339, timestamp)
+        if err != nil {
+            return nil, fmt.Errorf("parse sample timestamp: %w", err)
+        }
+
+        samples = append(samples, sample)
+    }
+
+    return samples, rows.Err()
+}
+
+// Levels lists the distinct level names via SELECT DISTINCT, instead of
+// pulling every sample back the way Query would.
+func (s *SQLiteStore) Levels(ctx context.Context) ([]string, error) {
+    rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT level FROM samples`)
+    if err != nil {
+        return nil, fmt.Errorf("query levels: %w", err)
+    }
+    defer rows.Close()
+
+    var levels []string
+    for rows.Next() {
+        var level string
+        if err := rows.Scan(&level); err != nil {
+            return nil, fmt.Errorf("scan level: %w", err)
+        }
+        levels = append(levels, level)
+    }
+
+    return levels, rows.Err()
+}
+
+func (s *SQLiteStore) Close() error {
+    return s.db.Close()
+}
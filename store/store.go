@@ -0,0 +1,115 @@
+// Package store abstracts the occupancy persistence layer behind a single
+// Store interface so the collector, and anything querying historical data,
+// doesn't need to know whether samples live in CSV, SQLite, Postgres or
+// Parquet.
+package store
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "time"
+)
+
+// LevelData mirrors one level's occupancy figures, independent of the
+// collector's own JSON decoding types.
+type LevelData struct {
+    Free    int
+    Total   int
+    FreePct float64
+    UsedPct float64
+}
+
+// Occupancy is a single polled snapshot, ready to persist.
+type Occupancy struct {
+    Telepen LevelData
+    Levels  map[string]LevelData
+}
+
+// Sample is a single level's reading at a point in time, as returned by Query.
+type Sample struct {
+    Timestamp time.Time `json:"timestamp"`
+    Level     string    `json:"level"`
+    Free      int       `json:"free"`
+    Total     int       `json:"total"`
+    FreePct   float64   `json:"freePct"`
+    UsedPct   float64   `json:"usedPct"`
+}
+
+// Query selects a historical range. Level filters to a single level
+// ("telepen" included); the zero value matches every level.
+type Query struct {
+    From  time.Time
+    To    time.Time
+    Level string
+}
+
+// Store persists occupancy samples and serves historical queries over them.
+type Store interface {
+    Write(ctx context.Context, timestamp time.Time, data Occupancy) error
+    Query(ctx context.Context, q Query) ([]Sample, error)
+
+    // Levels lists distinct level names without scanning the full history
+    // the way a Query with no time bound would. The SQL backends return
+    // every level ever written; the CSV and Parquet backends return the
+    // levels of a single write or file.
+    Levels(ctx context.Context) ([]string, error)
+
+    Close() error
+}
+
+// TelepenLevel is the pseudo-level name used for the telepen-gated figures
+// in Sample.Level and in query filters.
+const TelepenLevel = "telepen"
+
+// Backend names accepted by New.
+const (
+    BackendCSV      = "csv"
+    BackendSQLite   = "sqlite"
+    BackendPostgres = "postgres"
+    BackendParquet  = "parquet"
+)
+
+// Config carries the settings every backend might need; only the fields
+// relevant to the chosen Backend are read.
+type Config struct {
+    Backend string
+
+    // CSV
+    Path string
+
+    // SQLite / Postgres
+    DSN string
+
+    // Parquet
+    Dir string
+}
+
+// New builds the Store selected by cfg.Backend.
+func New(cfg Config) (Store, error) {
+    switch cfg.Backend {
+    case "", BackendCSV:
+        return NewCSVStore(cfg.Path)
+    case BackendSQLite:
+        return NewSQLiteStore(cfg.DSN)
+    case BackendPostgres:
+        return NewPostgresStore(cfg.DSN)
+    case BackendParquet:
+        return NewParquetStore(cfg.Dir)
+    default:
+        return nil, fmt.Errorf("unknown store backend: %q", cfg.Backend)
+    }
+}
+
+// orderedLevels returns the levels in data in a stable order, so writers
+// that need a fixed column/field order never disagree with themselves
+// across rows.
+func orderedLevels(data Occupancy) []string {
+    levels := make([]string, 0, len(data.Levels))
+    for level := range data.Levels {
+        levels = append(levels, level)
+    }
+
+    sort.Strings(levels)
+    return levels
+}